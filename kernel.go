@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/kubernetes-incubator/node-feature-discovery/pkg/source"
+)
+
+// defaultKernelConfigPath is where the kernel source looks for its module/config
+// allow-list, mirroring the source.d convention used for external sources.
+const defaultKernelConfigPath = "/etc/kubernetes/node-feature-discovery/kernel.conf"
+
+// kernelConfig describes which modules and kernel build options operators want
+// surfaced as node features. It is intentionally data-only so it can be
+// extended without recompiling NFD.
+type kernelConfig struct {
+	// Modules lists module names to probe for in /proc/modules and
+	// modules.builtin, e.g. "vfio_pci", "mlx5_core", "nvme", "ib_uverbs".
+	Modules []string `yaml:"modules"`
+	// ConfigOpts lists CONFIG_* symbols (without the CONFIG_ prefix) to look
+	// for in the running kernel's build config.
+	ConfigOpts []string `yaml:"configOpts"`
+}
+
+// defaultKernelConfig is used when no config file is present, covering the
+// modules and options most commonly needed for accelerator/RDMA scheduling.
+var defaultKernelConfig = kernelConfig{
+	Modules:    []string{"vfio_pci", "mlx5_core", "nvme", "ib_uverbs"},
+	ConfigOpts: []string{"NVME_CORE", "INFINIBAND", "VFIO_PCI"},
+}
+
+// Implements main.FeatureSource.
+type kernelSource struct {
+	// ConfigPath overrides the default location of the kernel source config file.
+	ConfigPath string
+	// ProcRoot overrides the "/proc" mount point, defaulting to "/proc".
+	ProcRoot string
+	// ModulesRoot overrides the "/lib/modules" tree, defaulting to "/lib/modules".
+	ModulesRoot string
+}
+
+func (s kernelSource) Name() string { return "kernel" }
+
+func (s kernelSource) procRoot() string {
+	if s.ProcRoot != "" {
+		return s.ProcRoot
+	}
+	return "/proc"
+}
+
+func (s kernelSource) configPath() string {
+	if s.ConfigPath != "" {
+		return s.ConfigPath
+	}
+	return defaultKernelConfigPath
+}
+
+func (s kernelSource) modulesRoot() string {
+	if s.ModulesRoot != "" {
+		return s.ModulesRoot
+	}
+	return "/lib/modules"
+}
+
+// config loads the module/config allow-list, falling back to defaultKernelConfig
+// when no config file is present.
+func (s kernelSource) config() (kernelConfig, error) {
+	data, err := ioutil.ReadFile(s.configPath())
+	if os.IsNotExist(err) {
+		return defaultKernelConfig, nil
+	} else if err != nil {
+		return kernelConfig{}, fmt.Errorf("can't read kernel source config %s: %s", s.configPath(), err.Error())
+	}
+
+	var cfg kernelConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return kernelConfig{}, fmt.Errorf("can't parse kernel source config %s: %s", s.configPath(), err.Error())
+	}
+	return cfg, nil
+}
+
+// Returns features describing the running kernel: loaded/builtin modules,
+// select build-time config options, the kernel version (as quantitative
+// major/minor features), and rdma-core userspace readiness.
+func (s kernelSource) Discover() ([]source.Feature, error) {
+	labels := []string{}
+	features := []source.Feature{}
+
+	cfg, err := s.config()
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.kernelRelease()
+	if err != nil {
+		stderrLogger.Printf("can't determine kernel release: %s", err.Error())
+	} else {
+		features = append(features, kernelVersionFeatures(release)...)
+	}
+
+	loaded, builtin, err := s.loadedModules()
+	if err != nil {
+		stderrLogger.Printf("can't determine loaded kernel modules: %s", err.Error())
+	} else {
+		for _, module := range cfg.Modules {
+			if loaded[module] || builtin[module] {
+				labels = append(labels, fmt.Sprintf("kernel-module.%s", module))
+			}
+		}
+	}
+
+	configSymbols, err := s.buildConfig(release)
+	if err != nil {
+		stderrLogger.Printf("can't determine kernel build config: %s", err.Error())
+	} else {
+		for _, opt := range cfg.ConfigOpts {
+			if configSymbols[opt] {
+				labels = append(labels, fmt.Sprintf("kernel-config.%s", opt))
+			}
+		}
+	}
+
+	if s.rdmaUserspaceOk() {
+		labels = append(labels, "kernel-rdma.userspace-ok")
+	}
+
+	return append(source.StringFeatures(labels), features...), nil
+}
+
+// kernelRelease returns the running kernel release string (e.g. "5.15.0-generic").
+func (s kernelSource) kernelRelease() (string, error) {
+	data, err := ioutil.ReadFile(path.Join(s.procRoot(), "sys/kernel/osrelease"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// kernelVersionFeatures splits a kernel release string into separate
+// major/minor features so nodeSelectors such as kernel-version.major=5 can
+// be used.
+func kernelVersionFeatures(release string) []source.Feature {
+	version := strings.SplitN(release, "-", 2)[0]
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return nil
+	}
+	return []source.Feature{
+		{Name: "kernel-version.major", Value: parts[0]},
+		{Name: "kernel-version.minor", Value: parts[1]},
+	}
+}
+
+// loadedModules reports, for every module known to the kernel, whether it is
+// currently loaded (/proc/modules) or compiled directly in (modules.builtin).
+func (s kernelSource) loadedModules() (loaded, builtin map[string]bool, err error) {
+	loaded = map[string]bool{}
+	builtin = map[string]bool{}
+
+	f, err := os.Open(path.Join(s.procRoot(), "modules"))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		loaded[fields[0]] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	release, err := s.kernelRelease()
+	if err != nil {
+		return loaded, builtin, nil
+	}
+	builtinPath := path.Join(s.modulesRoot(), release, "modules.builtin")
+	builtinFile, err := os.Open(builtinPath)
+	if err != nil {
+		// modules.builtin may legitimately be absent; loaded modules are still useful.
+		return loaded, builtin, nil
+	}
+	defer builtinFile.Close()
+
+	scanner = bufio.NewScanner(builtinFile)
+	for scanner.Scan() {
+		name := strings.TrimSuffix(filepath.Base(scanner.Text()), ".ko")
+		builtin[name] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return loaded, builtin, nil
+}
+
+// buildConfig parses the running kernel's build configuration, preferring
+// /proc/config.gz and falling back to /boot/config-<release>, and returns the
+// set of enabled ("y" or "m") CONFIG_* symbols, keyed without the CONFIG_ prefix.
+func (s kernelSource) buildConfig(release string) (map[string]bool, error) {
+	gz, err := os.Open(path.Join(s.procRoot(), "config.gz"))
+	if err == nil {
+		defer gz.Close()
+		zr, err := gzip.NewReader(gz)
+		if err != nil {
+			return nil, fmt.Errorf("can't decompress config.gz: %s", err.Error())
+		}
+		defer zr.Close()
+		return parseKernelConfig(bufio.NewScanner(zr))
+	}
+
+	if release == "" {
+		return nil, fmt.Errorf("no kernel release known and /proc/config.gz not available")
+	}
+	return s.buildConfigAt(fmt.Sprintf("/boot/config-%s", release))
+}
+
+// buildConfigAt parses a plaintext kernel build config file (as found under
+// /boot/config-<release>) for enabled CONFIG_* symbols.
+func (s kernelSource) buildConfigAt(configPath string) (map[string]bool, error) {
+	f, err := os.Open(configPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseKernelConfig(bufio.NewScanner(f))
+}
+
+// parseKernelConfig returns the set of CONFIG_* symbols enabled ("y" or "m"),
+// keyed without the CONFIG_ prefix.
+func parseKernelConfig(r *bufio.Scanner) (map[string]bool, error) {
+	symbols := map[string]bool{}
+	for r.Scan() {
+		line := strings.TrimSpace(r.Text())
+		if !strings.HasPrefix(line, "CONFIG_") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || (parts[1] != "y" && parts[1] != "m") {
+			continue
+		}
+		symbols[strings.TrimPrefix(parts[0], "CONFIG_")] = true
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
+
+// rdmaUserspaceOk validates that rdma-core userspace is usable: the kernel
+// exposes verbs character devices and the rdma CLI is installed. NFD only
+// validates; it never attempts to install packages.
+func (s kernelSource) rdmaUserspaceOk() bool {
+	matches, err := filepath.Glob("/dev/infiniband/uverbs*")
+	if err != nil || len(matches) == 0 {
+		return false
+	}
+	if _, err := exec.LookPath("rdma"); err != nil {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/kubernetes-incubator/node-feature-discovery/pkg/source"
+)
+
+func TestKernelVersionFeatures(t *testing.T) {
+	got := kernelVersionFeatures("5.15.0-generic")
+	want := []source.Feature{
+		{Name: "kernel-version.major", Value: "5"},
+		{Name: "kernel-version.minor", Value: "15"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("kernelVersionFeatures() = %v, want %v", got, want)
+	}
+}
+
+func TestLoadedModules(t *testing.T) {
+	root, err := ioutil.TempDir("", "nfd-proc")
+	if err != nil {
+		t.Fatalf("can't create temp proc root: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	writeFile(t, path.Join(root, "modules"), "mlx5_core 360448 0 - Live 0x0000000000000000\n")
+	writeFile(t, path.Join(root, "sys/kernel/osrelease"), "5.15.0-generic\n")
+	writeFile(t, path.Join(root, "modules-lib/5.15.0-generic/modules.builtin"), "kernel/drivers/nvme/host/nvme.ko\n")
+
+	s := kernelSource{ProcRoot: root, ModulesRoot: path.Join(root, "modules-lib")}
+	loaded, builtin, err := s.loadedModules()
+	if err != nil {
+		t.Fatalf("loadedModules() error: %s", err.Error())
+	}
+	if !loaded["mlx5_core"] {
+		t.Errorf("expected mlx5_core to be reported as loaded")
+	}
+	if !builtin["nvme"] {
+		t.Errorf("expected nvme to be reported as builtin")
+	}
+	if loaded["vfio_pci"] || builtin["vfio_pci"] {
+		t.Errorf("did not expect vfio_pci to be detected")
+	}
+}
+
+func TestBuildConfig(t *testing.T) {
+	root, err := ioutil.TempDir("", "nfd-proc")
+	if err != nil {
+		t.Fatalf("can't create temp proc root: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	bootDir, err := ioutil.TempDir("", "nfd-boot")
+	if err != nil {
+		t.Fatalf("can't create temp boot dir: %s", err.Error())
+	}
+	defer os.RemoveAll(bootDir)
+
+	writeFile(t, path.Join(bootDir, "config-5.15.0-generic"), "CONFIG_NVME_CORE=y\nCONFIG_VFIO_PCI=m\nCONFIG_FOO=n\n")
+
+	s := kernelSource{ProcRoot: root}
+	// buildConfig falls back to /boot/config-<release> when config.gz is absent;
+	// call buildConfigAt with our fixture's path directly instead.
+	symbols, err := s.buildConfigAt(path.Join(bootDir, "config-5.15.0-generic"))
+	if err != nil {
+		t.Fatalf("buildConfigAt() error: %s", err.Error())
+	}
+
+	got := []string{}
+	for sym := range symbols {
+		got = append(got, sym)
+	}
+	sort.Strings(got)
+	want := []string{"NVME_CORE", "VFIO_PCI"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildConfigAt() = %v, want %v", got, want)
+	}
+}
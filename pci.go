@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/kubernetes-incubator/node-feature-discovery/pkg/source"
+)
+
+// defaultPciConfigPath is where the pci source looks for its device allow-list.
+const defaultPciConfigPath = "/etc/kubernetes/node-feature-discovery/pci.conf"
+
+// defaultPciSysfsRoot is the sysfs mount point used when pciSource.SysfsRoot
+// is unset.
+const defaultPciSysfsRoot = "/sys"
+
+// pciDeviceRule describes a class of PCI devices operators want surfaced as
+// node features. Vendor and Class are PCI ID hex strings as found in sysfs
+// (e.g. vendor "10de" for NVIDIA, class "03" for display controllers);
+// either may be left empty to match any vendor or any class.
+type pciDeviceRule struct {
+	Vendor string `yaml:"vendor,omitempty"`
+	Class  string `yaml:"class,omitempty"`
+}
+
+// pciConfig is the operator-supplied allow-list of PCI devices to label.
+type pciConfig struct {
+	Devices []pciDeviceRule `yaml:"devices"`
+}
+
+// defaultPciConfig covers common accelerator vendors and network device
+// classes so the source is useful without any configuration.
+var defaultPciConfig = pciConfig{
+	Devices: []pciDeviceRule{
+		{Vendor: "10de"}, // NVIDIA
+		{Vendor: "1002"}, // AMD
+		{Vendor: "8086", Class: "03"}, // Intel GPUs
+		{Vendor: "15b3"}, // Mellanox
+		{Vendor: "14e4"}, // Broadcom
+		{Vendor: "10ee"}, // Xilinx
+		{Class: "02"},    // any network controller
+	},
+}
+
+// Implements main.FeatureSource.
+type pciSource struct {
+	// ConfigPath overrides the default location of the pci source config file.
+	ConfigPath string
+	// SysfsRoot overrides the sysfs mount point, defaulting to "/sys". It
+	// exists so tests can point discovery at a fake sysfs tree.
+	SysfsRoot string
+}
+
+func (s pciSource) Name() string { return "pci" }
+
+func (s pciSource) root() string {
+	if s.SysfsRoot != "" {
+		return s.SysfsRoot
+	}
+	return defaultPciSysfsRoot
+}
+
+func (s pciSource) configPath() string {
+	if s.ConfigPath != "" {
+		return s.ConfigPath
+	}
+	return defaultPciConfigPath
+}
+
+// config loads the device allow-list, falling back to defaultPciConfig when
+// no config file is present.
+func (s pciSource) config() (pciConfig, error) {
+	data, err := ioutil.ReadFile(s.configPath())
+	if os.IsNotExist(err) {
+		return defaultPciConfig, nil
+	} else if err != nil {
+		return pciConfig{}, fmt.Errorf("can't read pci source config %s: %s", s.configPath(), err.Error())
+	}
+
+	var cfg pciConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return pciConfig{}, fmt.Errorf("can't parse pci source config %s: %s", s.configPath(), err.Error())
+	}
+	return cfg, nil
+}
+
+// pciDevice holds the sysfs attributes of a single PCI device relevant to
+// matching and labelling.
+type pciDevice struct {
+	vendor        string // 4-hex-digit vendor ID, e.g. "10de"
+	class         string // 2-hex-digit base class, e.g. "03"
+	classSubclass string // 4-hex-digit class+subclass, e.g. "0300"
+}
+
+// Returns presence/count features for PCI devices matching the configured
+// allow-list, plus per-vendor SR-IOV capability and configuration labels.
+func (s pciSource) Discover() ([]source.Feature, error) {
+	cfg, err := s.config()
+	if err != nil {
+		return nil, err
+	}
+
+	devicesDir := path.Join(s.root(), "bus/pci/devices")
+	entries, err := ioutil.ReadDir(devicesDir)
+	if err != nil {
+		return nil, fmt.Errorf("can't list PCI devices under %s: %s", devicesDir, err.Error())
+	}
+
+	vendorCount := map[string]int{}
+	sriovCapable := map[string]bool{}
+	sriovConfigured := map[string]bool{}
+	classVendorSeen := map[string]bool{}
+
+	for _, entry := range entries {
+		devDir := path.Join(devicesDir, entry.Name())
+		dev, err := readPciDevice(devDir)
+		if err != nil {
+			stderrLogger.Printf("can't read PCI device attributes for %s: %s", entry.Name(), err.Error())
+			continue
+		}
+
+		matched := false
+		for _, rule := range cfg.Devices {
+			if !pciRuleMatches(rule, dev) {
+				continue
+			}
+			matched = true
+			if rule.Vendor != "" && rule.Class != "" {
+				classVendorSeen[fmt.Sprintf("%s_%s", dev.classSubclass, dev.vendor)] = true
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		vendorCount[dev.vendor]++
+
+		if total, num, err := sriovCounts(devDir); err == nil {
+			if total > 0 {
+				sriovCapable[dev.vendor] = true
+			}
+			if num > 0 {
+				sriovConfigured[dev.vendor] = true
+			}
+		}
+	}
+
+	labels := []string{}
+	features := []source.Feature{}
+	for vendor, count := range vendorCount {
+		labels = append(labels, fmt.Sprintf("pci-%s.present", vendor))
+		features = append(features, source.Feature{Name: fmt.Sprintf("pci-%s.count", vendor), Value: strconv.Itoa(count)})
+		if sriovCapable[vendor] {
+			labels = append(labels, fmt.Sprintf("pci-%s.sriov-capable", vendor))
+		}
+		if sriovConfigured[vendor] {
+			labels = append(labels, fmt.Sprintf("pci-%s.sriov-configured", vendor))
+		}
+	}
+	for key := range classVendorSeen {
+		labels = append(labels, fmt.Sprintf("pci-%s.present", key))
+	}
+
+	return append(source.StringFeatures(labels), features...), nil
+}
+
+// pciRuleMatches reports whether dev satisfies rule: an empty Vendor or Class
+// in the rule matches any value.
+func pciRuleMatches(rule pciDeviceRule, dev pciDevice) bool {
+	if rule.Vendor != "" && !strings.EqualFold(rule.Vendor, dev.vendor) {
+		return false
+	}
+	if rule.Class != "" && !strings.EqualFold(rule.Class, dev.class) {
+		return false
+	}
+	return true
+}
+
+// readPciDevice reads the vendor and class IDs of the PCI device at devDir.
+func readPciDevice(devDir string) (pciDevice, error) {
+	vendor, err := ioutil.ReadFile(path.Join(devDir, "vendor"))
+	if err != nil {
+		return pciDevice{}, err
+	}
+	class, err := ioutil.ReadFile(path.Join(devDir, "class"))
+	if err != nil {
+		return pciDevice{}, err
+	}
+
+	vendorID := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(string(vendor)), "0x"))
+	classID := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(string(class)), "0x"))
+	if len(classID) < 4 {
+		return pciDevice{}, fmt.Errorf("unexpected PCI class format: %q", classID)
+	}
+
+	return pciDevice{vendor: vendorID, class: classID[:2], classSubclass: classID[:4]}, nil
+}
+
+// sriovCounts reads the SR-IOV total and currently configured VF counts from
+// a PCI device's sysfs directory. Both bare PCI devices and the "device"
+// subtree of a netdev expose the same sriov_totalvfs/sriov_numvfs files, so
+// this is shared with networkSource.
+func sriovCounts(deviceDir string) (total, num int, err error) {
+	totalBytes, err := ioutil.ReadFile(path.Join(deviceDir, "sriov_totalvfs"))
+	if err != nil {
+		return 0, 0, err
+	}
+	total, err = strconv.Atoi(string(bytes.TrimSpace(totalBytes)))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	numBytes, err := ioutil.ReadFile(path.Join(deviceDir, "sriov_numvfs"))
+	if err != nil {
+		return total, 0, err
+	}
+	num, err = strconv.Atoi(string(bytes.TrimSpace(numBytes)))
+	if err != nil {
+		return total, 0, err
+	}
+	return total, num, nil
+}
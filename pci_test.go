@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"testing"
+)
+
+func TestDiscoverPci(t *testing.T) {
+	root, err := ioutil.TempDir("", "nfd-sysfs")
+	if err != nil {
+		t.Fatalf("can't create temp sysfs root: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	// NVIDIA GPU, no SR-IOV.
+	writeFile(t, path.Join(root, "bus/pci/devices/0000:3b:00.0/vendor"), "0x10de\n")
+	writeFile(t, path.Join(root, "bus/pci/devices/0000:3b:00.0/class"), "0x030000\n")
+
+	// Mellanox NIC with SR-IOV configured, matched both by vendor and the
+	// generic network-class rule.
+	writeFile(t, path.Join(root, "bus/pci/devices/0000:af:00.0/vendor"), "0x15b3\n")
+	writeFile(t, path.Join(root, "bus/pci/devices/0000:af:00.0/class"), "0x020000\n")
+	writeFile(t, path.Join(root, "bus/pci/devices/0000:af:00.0/sriov_totalvfs"), "8\n")
+	writeFile(t, path.Join(root, "bus/pci/devices/0000:af:00.0/sriov_numvfs"), "2\n")
+
+	// Unrelated device that matches no rule.
+	writeFile(t, path.Join(root, "bus/pci/devices/0000:00:1f.0/vendor"), "0x8086\n")
+	writeFile(t, path.Join(root, "bus/pci/devices/0000:00:1f.0/class"), "0x060100\n")
+
+	s := pciSource{SysfsRoot: root}
+	features, err := s.Discover()
+	if err != nil {
+		t.Fatalf("Discover() error: %s", err.Error())
+	}
+	got := featureNames(features)
+	sort.Strings(got)
+
+	want := []string{
+		"pci-10de.count",
+		"pci-10de.present",
+		"pci-15b3.count",
+		"pci-15b3.present",
+		"pci-15b3.sriov-capable",
+		"pci-15b3.sriov-configured",
+	}
+	sort.Strings(want)
+
+	if len(got) != len(want) {
+		t.Fatalf("Discover() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Discover() = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package external loads out-of-tree FeatureSources backed by executables,
+// letting vendors ship discovery logic without recompiling node-feature-discovery.
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-incubator/node-feature-discovery/pkg/source"
+)
+
+// DefaultSourceDir is where NFD looks for external source executables.
+const DefaultSourceDir = "/etc/kubernetes/node-feature-discovery/source.d/"
+
+// DefaultTimeout bounds how long an external source executable may run for
+// either the "name" or "discover" subcommand.
+const DefaultTimeout = 10 * time.Second
+
+// execSource adapts a single external executable to source.FeatureSource.
+// NFD invokes it as "<path> discover" and expects either a JSON array (of
+// typed feature objects or of feature names) or newline-delimited feature
+// names on stdout; see parseOutput.
+type execSource struct {
+	path    string
+	name    string
+	timeout time.Duration
+}
+
+func (s execSource) Name() string { return s.name }
+
+func (s execSource) Discover() ([]source.Feature, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.path, "discover")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("external source %s failed: %s", s.name, err.Error())
+	}
+
+	return parseOutput(stdout.Bytes())
+}
+
+// externalFeature is the typed JSON shape a vendor script may emit for a
+// quantitative or extended-resource feature, mirroring source.Feature.
+type externalFeature struct {
+	Name       string `json:"name"`
+	Value      string `json:"value"`
+	AsResource bool   `json:"asResource"`
+}
+
+// parseOutput accepts a JSON array of either typed {name,value,asResource}
+// objects, bare feature-name strings, or newline-delimited feature names,
+// matching what a vendor's discovery script is most likely to emit. Bare
+// names and newline-delimited output are treated as presence-only features.
+func parseOutput(out []byte) ([]source.Feature, error) {
+	trimmed := bytes.TrimSpace(out)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var typed []externalFeature
+		if err := json.Unmarshal(trimmed, &typed); err == nil {
+			features := make([]source.Feature, 0, len(typed))
+			for _, f := range typed {
+				features = append(features, source.Feature{Name: f.Name, Value: f.Value, AsResource: f.AsResource})
+			}
+			return features, nil
+		}
+
+		var names []string
+		if err := json.Unmarshal(trimmed, &names); err != nil {
+			return nil, fmt.Errorf("can't parse JSON feature list: %s", err.Error())
+		}
+		return source.StringFeatures(names), nil
+	}
+
+	names := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			names = append(names, line)
+		}
+	}
+	return source.StringFeatures(names), scanner.Err()
+}
+
+// Discover scans dir for executable files and registers one
+// source.FeatureSource per executable found with the "external:" namespace
+// prefix, e.g. a script whose "<path> name" output is "foo" is registered as
+// "external:foo". The file name is used instead only when that output is
+// empty.
+func Discover(dir string, timeout time.Duration) error {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("can't list external source directory %s: %s", dir, err.Error())
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Mode()&0111 == 0 {
+			continue
+		}
+		binPath := filepath.Join(dir, entry.Name())
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		nameOut, err := exec.CommandContext(ctx, binPath, "name").Output()
+		cancel()
+		if err != nil {
+			return fmt.Errorf("can't query name for external source %s: %s", binPath, err.Error())
+		}
+
+		name := strings.TrimSpace(string(nameOut))
+		if name == "" {
+			name = entry.Name()
+		}
+
+		source.Register("external:"+name, execSource{path: binPath, name: name, timeout: timeout})
+	}
+	return nil
+}
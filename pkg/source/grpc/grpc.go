@@ -0,0 +1,103 @@
+// Package grpc adapts a remote vendor daemon speaking the nfdgrpc.Labeler
+// service to the source.FeatureSource interface, for discovery logic that
+// doesn't fit the one-shot external executable model.
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	grpclib "google.golang.org/grpc"
+	"gopkg.in/yaml.v2"
+
+	"github.com/kubernetes-incubator/node-feature-discovery/pkg/source"
+	"github.com/kubernetes-incubator/node-feature-discovery/pkg/source/grpc/nfdgrpc"
+)
+
+// DefaultTimeout bounds how long NFD waits for a single Discover RPC,
+// including connection setup.
+const DefaultTimeout = 10 * time.Second
+
+// DefaultConfigPath is where Discover looks for its vendor daemon allow-list.
+const DefaultConfigPath = "/etc/kubernetes/node-feature-discovery/grpc.conf"
+
+// daemonConfig describes a single vendor daemon NFD should connect to over gRPC.
+type daemonConfig struct {
+	// Name identifies the daemon; it is registered as a source under "grpc:<Name>".
+	Name string `yaml:"name"`
+	// Address is the daemon's nfdgrpc.Labeler endpoint, e.g. "vendor-daemon:8080".
+	Address string `yaml:"address"`
+}
+
+// grpcConfig is the operator-supplied list of vendor daemons to load as sources.
+type grpcConfig struct {
+	Daemons []daemonConfig `yaml:"daemons"`
+}
+
+// remoteSource adapts a vendor daemon exposing nfdgrpc.Labeler at addr to
+// source.FeatureSource.
+type remoteSource struct {
+	name    string
+	addr    string
+	timeout time.Duration
+}
+
+// New returns a source.FeatureSource that discovers features by calling the
+// Labeler service exposed at addr. A zero timeout uses DefaultTimeout.
+func New(name, addr string, timeout time.Duration) source.FeatureSource {
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	return remoteSource{name: name, addr: addr, timeout: timeout}
+}
+
+func (s remoteSource) Name() string { return s.name }
+
+func (s remoteSource) Discover() ([]source.Feature, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	conn, err := grpclib.DialContext(ctx, s.addr, grpclib.WithInsecure(), grpclib.WithBlock())
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to external source %s at %s: %s", s.name, s.addr, err.Error())
+	}
+	defer conn.Close()
+
+	resp, err := nfdgrpc.NewLabelerClient(conn).Discover(ctx, &nfdgrpc.Empty{})
+	if err != nil {
+		return nil, fmt.Errorf("discover rpc to external source %s failed: %s", s.name, err.Error())
+	}
+
+	features := make([]source.Feature, 0, len(resp.Features))
+	for _, f := range resp.Features {
+		features = append(features, source.Feature{Name: f.Name, Value: f.Value, AsResource: f.AsResource})
+	}
+	return features, nil
+}
+
+// Discover loads the vendor daemon allow-list from configPath and registers
+// one source.FeatureSource per configured daemon under the "grpc:" namespace
+// (e.g. a daemon named "foo" is registered as "grpc:foo"), mirroring how
+// pkg/source/external registers its "external:"-namespaced sources. A zero
+// timeout uses DefaultTimeout for every registered daemon.
+func Discover(configPath string, timeout time.Duration) error {
+	data, err := ioutil.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("can't read grpc source config %s: %s", configPath, err.Error())
+	}
+
+	var cfg grpcConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("can't parse grpc source config %s: %s", configPath, err.Error())
+	}
+
+	for _, d := range cfg.Daemons {
+		source.Register("grpc:"+d.Name, New(d.Name, d.Address, timeout))
+	}
+	return nil
+}
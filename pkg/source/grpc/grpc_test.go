@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/kubernetes-incubator/node-feature-discovery/pkg/source"
+	"github.com/kubernetes-incubator/node-feature-discovery/pkg/source/grpc/nfdgrpc"
+)
+
+// fakeLabelerServer implements nfdgrpc.LabelerServer with a canned response,
+// standing in for a vendor daemon.
+type fakeLabelerServer struct {
+	features []*nfdgrpc.Feature
+}
+
+func (s *fakeLabelerServer) Discover(context.Context, *nfdgrpc.Empty) (*nfdgrpc.FeatureList, error) {
+	return &nfdgrpc.FeatureList{Features: s.features}, nil
+}
+
+// TestDiscoverRoundTrip exercises a real client/server call over the jsonCodec
+// registered in codec.go, proving Empty/Feature/FeatureList are actually
+// serializable despite not implementing proto.Message.
+func TestDiscoverRoundTrip(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	defer lis.Close()
+
+	srv := grpclib.NewServer()
+	nfdgrpc.RegisterLabelerServer(srv, &fakeLabelerServer{
+		features: []*nfdgrpc.Feature{
+			{Name: "network-sriov.vf-eth0", Value: "8", AsResource: true},
+		},
+	})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	dialer := func(context.Context, string) (net.Conn, error) { return lis.Dial() }
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpclib.DialContext(ctx, "bufnet", grpclib.WithInsecure(), grpclib.WithContextDialer(dialer), grpclib.WithBlock())
+	if err != nil {
+		t.Fatalf("DialContext() failed: %s", err)
+	}
+	defer conn.Close()
+
+	resp, err := nfdgrpc.NewLabelerClient(conn).Discover(ctx, &nfdgrpc.Empty{})
+	if err != nil {
+		t.Fatalf("Discover() failed: %s", err)
+	}
+
+	want := []source.Feature{{Name: "network-sriov.vf-eth0", Value: "8", AsResource: true}}
+	if len(resp.Features) != len(want) {
+		t.Fatalf("Discover() = %d features, want %d", len(resp.Features), len(want))
+	}
+	got := resp.Features[0]
+	if got.Name != want[0].Name || got.Value != want[0].Value || got.AsResource != want[0].AsResource {
+		t.Errorf("Discover() feature = %+v, want %+v", got, want[0])
+	}
+}
@@ -0,0 +1,94 @@
+// Hand-written to mirror api.proto: protoc and the protoc-gen-go/-go-grpc
+// plugins aren't available in this environment. Empty/Feature/FeatureList
+// intentionally don't implement proto.Message (no Reset/String/ProtoReflect);
+// they're (de)serialized by the JSON codec registered in codec.go instead of
+// the grpc-go default proto codec. Regenerate for real with:
+//
+//	protoc --go_out=. --go-grpc_out=. api.proto
+//
+// once protoc is available, replacing this file and codec.go.
+package nfdgrpc
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+)
+
+// Empty is an empty request message.
+type Empty struct{}
+
+// Feature is a single piece of node information discovered by a vendor
+// daemon, mirroring source.Feature.
+type Feature struct {
+	Name       string `json:"name,omitempty"`
+	Value      string `json:"value,omitempty"`
+	AsResource bool   `json:"as_resource,omitempty"`
+}
+
+// FeatureList carries the features discovered by an external source.
+type FeatureList struct {
+	Features []*Feature `json:"features,omitempty"`
+}
+
+// LabelerClient is the client API for the Labeler service.
+type LabelerClient interface {
+	Discover(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*FeatureList, error)
+}
+
+type labelerClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewLabelerClient returns a LabelerClient backed by cc.
+func NewLabelerClient(cc *grpc.ClientConn) LabelerClient {
+	return &labelerClient{cc}
+}
+
+func (c *labelerClient) Discover(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*FeatureList, error) {
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(CodecName)}, opts...)
+	out := new(FeatureList)
+	err := c.cc.Invoke(ctx, "/nfdgrpc.Labeler/Discover", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LabelerServer is the server API for the Labeler service.
+type LabelerServer interface {
+	Discover(context.Context, *Empty) (*FeatureList, error)
+}
+
+// RegisterLabelerServer registers srv to handle Labeler RPCs on s.
+func RegisterLabelerServer(s *grpc.Server, srv LabelerServer) {
+	s.RegisterService(&_Labeler_serviceDesc, srv)
+}
+
+func _Labeler_Discover_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LabelerServer).Discover(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/nfdgrpc.Labeler/Discover"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LabelerServer).Discover(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Labeler_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "nfdgrpc.Labeler",
+	HandlerType: (*LabelerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Discover",
+			Handler:    _Labeler_Discover_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api.proto",
+}
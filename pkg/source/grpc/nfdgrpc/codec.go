@@ -0,0 +1,33 @@
+package nfdgrpc
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// CodecName is the gRPC content-subtype under which jsonCodec is registered.
+// Clients must request it with grpc.CallContentSubtype(CodecName); the
+// server then picks the matching codec automatically from the request's
+// content-type header.
+const CodecName = "nfdjson"
+
+// jsonCodec (de)serializes Empty, Feature and FeatureList using encoding/json
+// rather than protobuf wire format, since those types don't implement
+// proto.Message and this tree has no protoc available to generate code that
+// would.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string { return CodecName }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
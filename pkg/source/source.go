@@ -0,0 +1,176 @@
+// Package source defines the FeatureSource interface used throughout
+// node-feature-discovery and a registry that both built-in and dynamically
+// loaded sources register themselves with.
+package source
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Feature is a single piece of discovered node information. Presence-style
+// features (the historical []string labels) have Value "true". Quantitative
+// features (CPU model name, cache sizes, VF counts, ...) carry their real
+// value instead, and may additionally be requested as a schedulable extended
+// resource via AsResource.
+type Feature struct {
+	Name string
+	// Value is the feature's value, rendered as a string the way it will
+	// appear in the resulting node label or extended resource quantity.
+	Value string
+	// AsResource requests that, in addition to being labelled, this feature
+	// is included in ExtendedResources' output for patching into the node's
+	// status.capacity as an extended resource (e.g.
+	// "nfd.node.k8s.io/network-sriov.vf-eth0"), so pods can request it
+	// directly. Nothing in this tree calls ExtendedResources yet; applying
+	// its output to the node is the responsibility of whatever owns the
+	// label-application loop.
+	AsResource bool
+}
+
+// StringFeatures adapts a slice of bare feature names, as returned by the
+// historical []string-only sources, to []Feature by giving each the value
+// "true". This lets presence-only sources keep their existing Discover logic.
+func StringFeatures(names []string) []Feature {
+	features := make([]Feature, 0, len(names))
+	for _, name := range names {
+		features = append(features, Feature{Name: name, Value: "true"})
+	}
+	return features
+}
+
+// extendedResourcePrefix namespaces extended resources NFD advertises on the
+// node.
+const extendedResourcePrefix = "nfd.node.k8s.io/"
+
+// ExtendedResources reduces features to the map of extended resource names to
+// quantities that should be patched into the node's status.capacity, keeping
+// only the features that requested it via AsResource. It does not talk to the
+// Kubernetes API itself, nor is it called anywhere in this tree yet: applying
+// the returned map to a node's status.capacity is the responsibility of
+// whatever owns the label-application loop.
+func ExtendedResources(features []Feature) map[string]string {
+	resources := map[string]string{}
+	for _, f := range features {
+		if f.AsResource {
+			resources[extendedResourcePrefix+f.Name] = f.Value
+		}
+	}
+	return resources
+}
+
+// FeatureSource represents a source of discovered node features.
+type FeatureSource interface {
+	// Returns a friendly name for this source of node features.
+	Name() string
+
+	// Returns discovered features for this node.
+	Discover() ([]Feature, error)
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]FeatureSource{}
+)
+
+// Register adds a FeatureSource to the default registry under name,
+// overwriting any previously registered source of the same name. Built-in
+// sources call this from an init() function; the external and gRPC loaders
+// call it once per discovered vendor source.
+func Register(name string, s FeatureSource) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[name] = s
+}
+
+// Get looks up a previously registered FeatureSource by name.
+func Get(name string) (FeatureSource, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := registry[name]
+	return s, ok
+}
+
+// Names returns the names of all currently registered sources, sorted.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// All returns every currently registered FeatureSource.
+func All() []FeatureSource {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	all := make([]FeatureSource, 0, len(registry))
+	for _, s := range registry {
+		all = append(all, s)
+	}
+	return all
+}
+
+// SourcesFlag is a parsed --sources flag value. A bare name (e.g. "cpuid")
+// replaces NFD's default set of sources; a name prefixed with "+" is additive
+// and enables a source on top of the default (or replacement) set, e.g.
+// "+external:foo" for a vendor source loaded from the source.d directory.
+type SourcesFlag struct {
+	// Replace, if non-empty, is used instead of the default source list.
+	Replace []string
+	// Additive is appended on top of Replace (or the default list, if Replace
+	// is empty).
+	Additive []string
+}
+
+// Resolve combines the parsed flag with defaults into the final ordered list
+// of source names to enable: Replace if given, else defaults, followed by
+// Additive.
+func (f SourcesFlag) Resolve(defaults []string) []string {
+	names := defaults
+	if len(f.Replace) > 0 {
+		names = f.Replace
+	}
+	return append(append([]string{}, names...), f.Additive...)
+}
+
+// ParseSourcesFlag parses a --sources flag value such as "cpuid,rdt,+external:foo"
+// into the replacement and additive source names it selects.
+func ParseSourcesFlag(flag string) SourcesFlag {
+	var parsed SourcesFlag
+	for _, tok := range strings.Split(flag, ",") {
+		tok = strings.TrimSpace(tok)
+		if tok == "" {
+			continue
+		}
+		if strings.HasPrefix(tok, "+") {
+			parsed.Additive = append(parsed.Additive, strings.TrimPrefix(tok, "+"))
+		} else {
+			parsed.Replace = append(parsed.Replace, tok)
+		}
+	}
+	return parsed
+}
+
+// Select looks up each name in the registry and returns the FeatureSources
+// found, in the order given, silently skipping any name that isn't
+// registered (e.g. an external source not present on this node). It is how
+// a parsed SourcesFlag gets turned into the sources NFD actually runs.
+func Select(names []string) []FeatureSource {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	selected := make([]FeatureSource, 0, len(names))
+	for _, name := range names {
+		if s, ok := registry[name]; ok {
+			selected = append(selected, s)
+		}
+	}
+	return selected
+}
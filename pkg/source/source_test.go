@@ -0,0 +1,74 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fakeSource string
+
+func (s fakeSource) Name() string                 { return string(s) }
+func (s fakeSource) Discover() ([]Feature, error) { return nil, nil }
+
+func TestRegisterAndGet(t *testing.T) {
+	Register("fake", fakeSource("fake"))
+
+	got, ok := Get("fake")
+	if !ok {
+		t.Fatalf("Get(\"fake\") did not find a registered source")
+	}
+	if got.Name() != "fake" {
+		t.Errorf("Get(\"fake\").Name() = %q, want %q", got.Name(), "fake")
+	}
+}
+
+func TestExtendedResources(t *testing.T) {
+	features := []Feature{
+		{Name: "network-sriov.vf-eth0", Value: "8", AsResource: true},
+		{Name: "cpu-model.name", Value: "Foo CPU"},
+	}
+	got := ExtendedResources(features)
+	want := map[string]string{"nfd.node.k8s.io/network-sriov.vf-eth0": "8"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtendedResources() = %v, want %v", got, want)
+	}
+}
+
+func TestParseSourcesFlag(t *testing.T) {
+	got := ParseSourcesFlag("cpuid,rdt,+external:foo")
+	want := SourcesFlag{Replace: []string{"cpuid", "rdt"}, Additive: []string{"external:foo"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSourcesFlag() = %v, want %v", got, want)
+	}
+}
+
+func TestSourcesFlagResolve(t *testing.T) {
+	defaults := []string{"cpuid", "rdt", "pstate"}
+
+	additiveOnly := SourcesFlag{Additive: []string{"external:foo"}}
+	got := additiveOnly.Resolve(defaults)
+	want := []string{"cpuid", "rdt", "pstate", "external:foo"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SourcesFlag{Additive: ...}.Resolve() = %v, want %v", got, want)
+	}
+
+	replace := SourcesFlag{Replace: []string{"cpuid"}, Additive: []string{"external:foo"}}
+	got = replace.Resolve(defaults)
+	want = []string{"cpuid", "external:foo"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SourcesFlag{Replace: ...}.Resolve() = %v, want %v", got, want)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	Register("select-fake-a", fakeSource("select-fake-a"))
+	Register("select-fake-b", fakeSource("select-fake-b"))
+
+	got := Select([]string{"select-fake-b", "select-fake-a", "not-registered"})
+	if len(got) != 2 {
+		t.Fatalf("Select() = %v, want 2 sources", got)
+	}
+	if got[0].Name() != "select-fake-b" || got[1].Name() != "select-fake-a" {
+		t.Errorf("Select() = %v, want [select-fake-b select-fake-a]", got)
+	}
+}
@@ -0,0 +1,9 @@
+// +build amd64
+
+package main
+
+// cpuidLeaf executes the CPUID instruction for the given EAX leaf and ECX
+// sub-leaf, returning the raw EAX/EBX/ECX/EDX register values. Implemented in
+// assembly because the RDT enumeration leaves (0x7, 0x10, 0xF) aren't
+// exposed by the high-level feature flags in github.com/klauspost/cpuid.
+func cpuidLeaf(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
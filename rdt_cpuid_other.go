@@ -0,0 +1,9 @@
+// +build !amd64
+
+package main
+
+// cpuidLeaf is a no-op stub on non-amd64 architectures, where RDT doesn't
+// exist; rdtSource.Discover treats an all-zero result as "nothing detected".
+func cpuidLeaf(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32) {
+	return 0, 0, 0, 0
+}
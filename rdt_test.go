@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"testing"
+)
+
+func TestDiscoverRdtFromResctrl(t *testing.T) {
+	root, err := ioutil.TempDir("", "nfd-resctrl")
+	if err != nil {
+		t.Fatalf("can't create temp resctrl root: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	if err := os.MkdirAll(path.Join(root, "info/L3_MON"), 0755); err != nil {
+		t.Fatalf("can't create L3_MON info dir: %s", err.Error())
+	}
+	if err := os.MkdirAll(path.Join(root, "info/L3"), 0755); err != nil {
+		t.Fatalf("can't create L3 info dir: %s", err.Error())
+	}
+
+	s := rdtSource{ResctrlRoot: root}
+	features, err := s.Discover()
+	if err != nil {
+		t.Fatalf("Discover() error: %s", err.Error())
+	}
+	got := featureNames(features)
+	sort.Strings(got)
+	want := []string{"rdt.RDTCMT", "rdt.RDTL3CA", "rdt.RDTMBM", "rdt.RDTMON"}
+	sort.Strings(want)
+
+	if len(got) != len(want) {
+		t.Fatalf("Discover() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Discover() = %v, want %v", got, want)
+		}
+	}
+}
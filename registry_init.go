@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/kubernetes-incubator/node-feature-discovery/pkg/source"
+)
+
+// init registers the built-in feature sources with pkg/source's registry so
+// they can be selected via the "--sources" CLI flag alongside externally
+// loaded sources, instead of living in a hardcoded slice.
+func init() {
+	source.Register("cpuid", cpuidSource{})
+	source.Register("rdt", rdtSource{})
+	source.Register("pstate", pstateSource{})
+	source.Register("network", networkSource{})
+	source.Register("kernel", kernelSource{})
+	source.Register("pci", pciSource{})
+}
@@ -5,28 +5,20 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
-	"os/exec"
 	"path"
 	"strconv"
 	"strings"
 
 	"github.com/klauspost/cpuid"
-)
-
-// FeatureSource represents a source of discovered node features.
-type FeatureSource interface {
-	// Returns a friendly name for this source of node features.
-	Name() string
-
-	// Returns discovered features for this node.
-	Discover() ([]string, error)
-}
 
-const (
-	// RDTBin is the path to RDT detection helpers.
-	RDTBin = "/go/src/github.com/kubernetes-incubator/node-feature-discovery/rdt-discovery"
+	"github.com/kubernetes-incubator/node-feature-discovery/pkg/source"
 )
 
+// FeatureSource represents a source of discovered node features. It is kept
+// as an alias of source.FeatureSource so existing callers in this package
+// don't need to change, while the registry and loaders live in pkg/source.
+type FeatureSource = source.FeatureSource
+
 ////////////////////////////////////////////////////////////////////////////////
 // CPUID Source
 
@@ -35,49 +27,152 @@ type cpuidSource struct{}
 
 func (s cpuidSource) Name() string { return "cpuid" }
 
-// Returns feature names for all the supported CPU features.
-func (s cpuidSource) Discover() ([]string, error) {
+// Returns features for all the supported CPU features, plus the CPU model
+// name and cache sizes as quantitative features.
+func (s cpuidSource) Discover() ([]source.Feature, error) {
 	// Get the cpu features as strings
-	return cpuid.CPU.Features.Strings(), nil
+	features := source.StringFeatures(cpuid.CPU.Features.Strings())
+
+	if cpuid.CPU.BrandName != "" {
+		features = append(features, source.Feature{Name: "cpu-model.name", Value: cpuid.CPU.BrandName})
+	}
+
+	caches := []struct {
+		name string
+		size int
+	}{
+		{"l1d", cpuid.CPU.Cache.L1D},
+		{"l1i", cpuid.CPU.Cache.L1I},
+		{"l2", cpuid.CPU.Cache.L2},
+		{"l3", cpuid.CPU.Cache.L3},
+	}
+	for _, cache := range caches {
+		if cache.size > 0 {
+			features = append(features, source.Feature{Name: fmt.Sprintf("cpu-cache.%s-kb", cache.name), Value: strconv.Itoa(cache.size / 1024)})
+		}
+	}
+
+	return features, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // RDT (Intel Resource Director Technology) Source
 
+// defaultResctrlRoot is where the kernel mounts the resctrl filesystem.
+const defaultResctrlRoot = "/sys/fs/resctrl"
+
 // Implements main.FeatureSource.
-type rdtSource struct{}
+type rdtSource struct {
+	// ResctrlRoot overrides the resctrl mount point, defaulting to
+	// "/sys/fs/resctrl". It exists so tests can point discovery at a fake
+	// resctrl tree.
+	ResctrlRoot string
+}
 
 func (s rdtSource) Name() string { return "rdt" }
 
-// Returns feature names for CMT and CAT if suppported.
-func (s rdtSource) Discover() ([]string, error) {
-	features := []string{}
+func (s rdtSource) resctrlRoot() string {
+	if s.ResctrlRoot != "" {
+		return s.ResctrlRoot
+	}
+	return defaultResctrlRoot
+}
 
-	cmd := exec.Command("bash", "-c", path.Join(RDTBin, "mon-discovery"))
-	if err := cmd.Run(); err != nil {
-		stderrLogger.Printf("support for RDT monitoring was not detected: %s", err.Error())
-	} else {
-		// RDT monitoring detected.
-		features = append(features, "RDTMON")
+// Returns features for RDT monitoring and allocation support without
+// shelling out to helper binaries. When resctrl is mounted its info
+// directory is read; otherwise support is detected directly from CPUID
+// leaves 0x7, 0xF and 0x10. CBM length and CLOSID counts are reported as
+// quantitative features only on the CPUID path.
+func (s rdtSource) Discover() ([]source.Feature, error) {
+	infoDir := path.Join(s.resctrlRoot(), "info")
+	if _, err := ioutil.ReadDir(infoDir); err == nil {
+		return rdtFeaturesFromResctrl(infoDir), nil
 	}
+	return rdtFeaturesFromCpuid(), nil
+}
 
-	cmd = exec.Command("bash", "-c", path.Join(RDTBin, "l3-alloc-discovery"))
-	if err := cmd.Run(); err != nil {
-		stderrLogger.Printf("support for RDT L3 allocation was not detected: %s", err.Error())
-	} else {
-		// RDT L3 cache allocation detected.
-		features = append(features, "RDTL3CA")
+// rdtFeaturesFromCpuid enumerates RDT support from raw CPUID leaves:
+//   - leaf 0x7, sub-leaf 0, EBX bit 15: Platform QoS Enforcement (PQE)
+//   - leaf 0xF,  sub-leaf 0: Platform QoS Monitoring (PQM) and max RMID
+//   - leaf 0xF,  sub-leaf 1: L3 monitoring capabilities (CMT, MBM local/total)
+//   - leaf 0x10, sub-leaf 0: which allocation resources are supported
+//   - leaf 0x10, sub-leaves 1-3: L3 CAT, L2 CAT and MBA parameters
+func rdtFeaturesFromCpuid() []source.Feature {
+	features := []source.Feature{}
+
+	_, leaf7Ebx, _, _ := cpuidLeaf(0x7, 0)
+	pqe := leaf7Ebx&(1<<15) != 0
+
+	_, _, _, leafFEdx := cpuidLeaf(0xF, 0)
+	pqm := leafFEdx&(1<<1) != 0
+	if pqm {
+		features = append(features, source.Feature{Name: "rdt.RDTMON", Value: "true"})
+
+		_, _, _, monEdx := cpuidLeaf(0xF, 1)
+		if monEdx&(1<<0) != 0 {
+			features = append(features, source.Feature{Name: "rdt.RDTCMT", Value: "true"})
+		}
+		if monEdx&(1<<1) != 0 || monEdx&(1<<2) != 0 {
+			features = append(features, source.Feature{Name: "rdt.RDTMBM", Value: "true"})
+		}
 	}
 
-	cmd = exec.Command("bash", "-c", path.Join(RDTBin, "l2-alloc-discovery"))
-	if err := cmd.Run(); err != nil {
-		stderrLogger.Printf("support for RDT L2 allocation was not detected: %s", err.Error())
-	} else {
-		// RDT L2 cache allocation detected.
-		features = append(features, "RDTL2CA")
+	if !pqe {
+		return features
 	}
 
-	return features, nil
+	_, allocEbx, _, _ := cpuidLeaf(0x10, 0)
+	if allocEbx&(1<<1) != 0 {
+		// L3 Cache Allocation Technology.
+		eax, _, _, edx := cpuidLeaf(0x10, 1)
+		features = append(features,
+			source.Feature{Name: "rdt.RDTL3CA", Value: "true"},
+			source.Feature{Name: "rdt.RDTL3CA.cbmlen", Value: fmt.Sprintf("%d", (eax&0x1f)+1)},
+			source.Feature{Name: "rdt.RDTL3CA.closids", Value: fmt.Sprintf("%d", (edx&0xffff)+1)},
+		)
+	}
+	if allocEbx&(1<<2) != 0 {
+		// L2 Cache Allocation Technology.
+		eax, _, _, edx := cpuidLeaf(0x10, 2)
+		features = append(features,
+			source.Feature{Name: "rdt.RDTL2CA", Value: "true"},
+			source.Feature{Name: "rdt.RDTL2CA.cbmlen", Value: fmt.Sprintf("%d", (eax&0x1f)+1)},
+			source.Feature{Name: "rdt.RDTL2CA.closids", Value: fmt.Sprintf("%d", (edx&0xffff)+1)},
+		)
+	}
+	if allocEbx&(1<<3) != 0 {
+		// Memory Bandwidth Allocation.
+		eax, _, _, edx := cpuidLeaf(0x10, 3)
+		features = append(features,
+			source.Feature{Name: "rdt.RDTMBA", Value: "true"},
+			source.Feature{Name: "rdt.RDTMBA.maxthrottle", Value: fmt.Sprintf("%d", (eax&0xfff)+1)},
+			source.Feature{Name: "rdt.RDTMBA.closids", Value: fmt.Sprintf("%d", (edx&0xffff)+1)},
+		)
+	}
+
+	return features
+}
+
+// rdtFeaturesFromResctrl maps the resctrl info directories under infoDir to
+// the same presence features rdtFeaturesFromCpuid would report, without
+// needing the compiled rdt-discovery C helpers this used to shell out to.
+func rdtFeaturesFromResctrl(infoDir string) []source.Feature {
+	names := []string{}
+
+	if _, err := ioutil.ReadDir(path.Join(infoDir, "L3_MON")); err == nil {
+		names = append(names, "rdt.RDTMON", "rdt.RDTCMT", "rdt.RDTMBM")
+	}
+	if _, err := ioutil.ReadDir(path.Join(infoDir, "L3")); err == nil {
+		names = append(names, "rdt.RDTL3CA")
+	}
+	if _, err := ioutil.ReadDir(path.Join(infoDir, "L2")); err == nil {
+		names = append(names, "rdt.RDTL2CA")
+	}
+	if _, err := ioutil.ReadDir(path.Join(infoDir, "MB")); err == nil {
+		names = append(names, "rdt.RDTMBA")
+	}
+
+	return source.StringFeatures(names)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -89,9 +184,7 @@ type pstateSource struct{}
 func (s pstateSource) Name() string { return "pstate" }
 
 // Returns feature names for p-state related features such as turbo boost.
-func (s pstateSource) Discover() ([]string, error) {
-	features := []string{}
-
+func (s pstateSource) Discover() ([]source.Feature, error) {
 	// Only looking for turbo boost for now...
 	bytes, err := ioutil.ReadFile("/sys/devices/system/cpu/intel_pstate/no_turbo")
 	if err != nil {
@@ -99,67 +192,284 @@ func (s pstateSource) Discover() ([]string, error) {
 	}
 	if bytes[0] == byte('0') {
 		// Turbo boost is enabled.
-		features = append(features, "turbo")
+		return source.StringFeatures([]string{"turbo"}), nil
 	}
 
-	return features, nil
+	return nil, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // Network Source
 
+// defaultSysfsRoot is the sysfs mount point used when networkSource.SysfsRoot is unset.
+const defaultSysfsRoot = "/sys"
+
 // Implements main.FeatureSource.
-type networkSource struct{}
+type networkSource struct {
+	// SysfsRoot overrides the sysfs mount point, defaulting to "/sys". It exists
+	// so tests can point discovery at a fake sysfs tree.
+	SysfsRoot string
+	// interfaces overrides net.Interfaces, the list of interfaces discovery
+	// iterates. It exists so tests can pair a fixed interface list with a
+	// fake sysfs tree.
+	interfaces func() ([]net.Interface, error)
+}
 
 func (s networkSource) Name() string { return "network" }
 
+// interfaceList returns the network interfaces to probe.
+func (s networkSource) interfaceList() ([]net.Interface, error) {
+	if s.interfaces != nil {
+		return s.interfaces()
+	}
+	return net.Interfaces()
+}
+
+// root returns the sysfs mount point to read from.
+func (s networkSource) root() string {
+	if s.SysfsRoot != "" {
+		return s.SysfsRoot
+	}
+	return defaultSysfsRoot
+}
+
 // reading the network card details from sysfs and determining if SR-IOV is enabled for each of the network interfaces
-func (s networkSource) Discover() ([]string, error) {
-	features := []string{}
-	netInterfaces, err := net.Interfaces()
+func (s networkSource) Discover() ([]source.Feature, error) {
+	labels := []string{}
+	features := []source.Feature{}
+
+	netInterfaces, err := s.interfaceList()
 	if err != nil {
 		return nil, fmt.Errorf("can't obtain the network interfaces details: %s", err.Error())
 	}
 	// iterating through network interfaces to obtain their respective number of virtual functions
 	for _, netInterface := range netInterfaces {
-		if strings.Contains(netInterface.Flags.String(), "up") && !strings.Contains(netInterface.Flags.String(), "loopback") {
-			totalVfsPath := "/sys/class/net/" + netInterface.Name + "/device/sriov_totalvfs"
-			totalBytes, err := ioutil.ReadFile(totalVfsPath)
-			if err != nil {
-				stderrLogger.Printf("SR-IOV not supported for network interface: %s: %s", netInterface.Name, err.Error())
-				continue
+		if !strings.Contains(netInterface.Flags.String(), "up") || strings.Contains(netInterface.Flags.String(), "loopback") {
+			continue
+		}
+
+		sriovLabels, sriovFeatures := s.discoverSriov(netInterface.Name)
+		labels = append(labels, sriovLabels...)
+		features = append(features, sriovFeatures...)
+
+		// These report other NIC capabilities independent of SR-IOV, so they
+		// must run for every up, non-loopback interface rather than only the
+		// ones with sriov_totalvfs present.
+		labels = append(labels, s.discoverSwitchdev(netInterface.Name)...)
+		labels = append(labels, s.discoverVfRepresentors(netInterface.Name)...)
+		labels = append(labels, s.discoverVendorDevice(netInterface.Name)...)
+	}
+
+	labels = append(labels, s.discoverRdma()...)
+
+	return append(source.StringFeatures(labels), features...), nil
+}
+
+// discoverSriov reports SR-IOV capability and configuration for iface,
+// returning the maximum VF count as a feature; the configured VF count only
+// decides the "sriov-configured" label. The
+// sysfs layout it reads is identical to a bare PCI device's, so the actual
+// parsing is shared with pci.go via sriovCounts.
+func (s networkSource) discoverSriov(iface string) ([]string, []source.Feature) {
+	labels := []string{}
+	features := []source.Feature{}
+
+	deviceDir := path.Join(s.root(), "class/net", iface, "device")
+	t, n, err := sriovCounts(deviceDir)
+	if err != nil && t == 0 {
+		stderrLogger.Printf("SR-IOV not supported for network interface: %s: %s", iface, err.Error())
+		return labels, features
+	}
+	if t <= 0 {
+		return labels, features
+	}
+
+	stdoutLogger.Printf("SR-IOV capability is detected on the network interface: %s", iface)
+	stdoutLogger.Printf("%d maximum supported number of virtual functions on network interface: %s", t, iface)
+	labels = append(labels, "sriov")
+	features = append(features, source.Feature{
+		Name:       fmt.Sprintf("network-sriov.vf-%s", iface),
+		Value:      strconv.Itoa(t),
+		AsResource: true,
+	})
+
+	if err != nil {
+		stderrLogger.Printf("SR-IOV not configured for network interface: %s: %s", iface, err.Error())
+		return labels, features
+	}
+	if n > 0 {
+		stderrLogger.Printf("%d virtual functions configured on network interface: %s", n, iface)
+		labels = append(labels, "sriov-configured")
+	} else {
+		stderrLogger.Printf("SR-IOV not configured on network interface: %s", iface)
+	}
+	return labels, features
+}
+
+// discoverSwitchdev reports the e-switch mode (switchdev or legacy) of a PF, if any.
+func (s networkSource) discoverSwitchdev(iface string) []string {
+	modePath := path.Join(s.root(), "class/net", iface, "compat/devlink/mode")
+	modeBytes, err := ioutil.ReadFile(modePath)
+	if err != nil {
+		// Not every NIC exposes a devlink compat mode; this is expected for most.
+		return nil
+	}
+	mode := strings.TrimSpace(string(modeBytes))
+	if mode != "switchdev" && mode != "legacy" {
+		stderrLogger.Printf("unrecognized devlink mode %q for network interface: %s", mode, iface)
+		return nil
+	}
+	return []string{fmt.Sprintf("network-sriov.switchmode-%s-%s", mode, iface)}
+}
+
+// discoverVfRepresentors detects whether iface is a PF with VF representor netdevs,
+// identified as another netdev sharing its phys_switch_id and exposing a non-empty phys_port_name.
+func (s networkSource) discoverVfRepresentors(iface string) []string {
+	switchIDPath := path.Join(s.root(), "class/net", iface, "phys_switch_id")
+	pfSwitchID, err := ioutil.ReadFile(switchIDPath)
+	if err != nil || len(bytes.TrimSpace(pfSwitchID)) == 0 {
+		return nil
+	}
+
+	netDir := path.Join(s.root(), "class/net")
+	entries, err := ioutil.ReadDir(netDir)
+	if err != nil {
+		stderrLogger.Printf("can't list network interfaces under %s: %s", netDir, err.Error())
+		return nil
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == iface {
+			continue
+		}
+		otherSwitchID, err := ioutil.ReadFile(path.Join(netDir, name, "phys_switch_id"))
+		if err != nil || !bytes.Equal(bytes.TrimSpace(otherSwitchID), bytes.TrimSpace(pfSwitchID)) {
+			continue
+		}
+		portName, err := ioutil.ReadFile(path.Join(netDir, name, "phys_port_name"))
+		if err != nil {
+			continue
+		}
+		if len(bytes.TrimSpace(portName)) > 0 {
+			return []string{fmt.Sprintf("network-sriov.vf-representors-%s", iface)}
+		}
+	}
+	return nil
+}
+
+// discoverVendorDevice resolves the PCI vendor/device IDs of iface against the
+// hwdata PCI ID database to emit human-readable vendor/device labels.
+func (s networkSource) discoverVendorDevice(iface string) []string {
+	devDir := path.Join(s.root(), "class/net", iface, "device")
+	vendor, err := ioutil.ReadFile(path.Join(devDir, "vendor"))
+	if err != nil {
+		return nil
+	}
+	device, err := ioutil.ReadFile(path.Join(devDir, "device"))
+	if err != nil {
+		return nil
+	}
+
+	vendorID := strings.TrimSpace(string(vendor))
+	deviceID := strings.TrimSpace(string(device))
+	name, ok := lookupPciID(vendorID, deviceID)
+	if !ok {
+		return nil
+	}
+	return []string{fmt.Sprintf("network-device.%s-%s", name, iface)}
+}
+
+// discoverRdma probes /sys/class/infiniband for RDMA-capable devices and, for
+// each, determines the link protocol in use (RoCE, iWARP, or plain InfiniBand).
+func (s networkSource) discoverRdma() []string {
+	ibDir := path.Join(s.root(), "class/infiniband")
+	entries, err := ioutil.ReadDir(ibDir)
+	if err != nil {
+		// No RDMA devices registered with the kernel.
+		return nil
+	}
+
+	features := []string{}
+	for _, entry := range entries {
+		dev := entry.Name()
+		features = append(features, "network-rdma.capable")
+
+		linkLayerPath := path.Join(ibDir, dev, "ports/1/link_layer")
+		linkLayerBytes, err := ioutil.ReadFile(linkLayerPath)
+		if err != nil {
+			stderrLogger.Printf("can't determine RDMA link layer for device: %s: %s", dev, err.Error())
+			continue
+		}
+		linkLayer := strings.TrimSpace(string(linkLayerBytes))
+
+		switch linkLayer {
+		case "Ethernet":
+			// Both RoCE and iWARP run over Ethernet; node_type disambiguates them.
+			// iWARP adapters report a node_type of "4: RNIC" rather than the
+			// literal string "iWARP".
+			nodeType, err := ioutil.ReadFile(path.Join(ibDir, dev, "node_type"))
+			if err == nil && strings.Contains(string(nodeType), "RNIC") {
+				features = append(features, "network-rdma.iwarp")
+			} else {
+				features = append(features, "network-rdma.roce")
 			}
-			total := bytes.TrimSpace(totalBytes)
-			t, err := strconv.Atoi(string(total))
-			if err != nil {
-				stderrLogger.Printf("Error in obtaining maximum supported number of virtual functions for network interface: %s: %s", netInterface.Name, err.Error())
+		case "InfiniBand":
+			features = append(features, "network-rdma.ib")
+		default:
+			stderrLogger.Printf("unrecognized RDMA link layer %q for device: %s", linkLayer, dev)
+		}
+	}
+	return features
+}
+
+// pciIDDatabase is the default location of the hwdata PCI ID database.
+const pciIDDatabase = "/usr/share/hwdata/pci.ids"
+
+// lookupPciID resolves a (vendorID, deviceID) pair, as found under a PCI
+// device's sysfs "vendor"/"device" files (e.g. "0x15b3"), to a short,
+// label-safe "vendor" or "vendor-device" name using the hwdata PCI ID
+// database. The device name is only included when found; an unknown device
+// under a known vendor still resolves to the vendor name alone.
+func lookupPciID(vendorID, deviceID string) (string, bool) {
+	vendorID = strings.ToLower(strings.TrimPrefix(vendorID, "0x"))
+	deviceID = strings.ToLower(strings.TrimPrefix(deviceID, "0x"))
+
+	data, err := ioutil.ReadFile(pciIDDatabase)
+	if err != nil {
+		return "", false
+	}
+
+	// Keep labels short and nodeSelector-friendly: first word only, lowercased.
+	firstWord := func(s string) string {
+		return strings.ToLower(strings.Fields(strings.TrimSpace(s))[0])
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "#") || line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "  ", 2)
+		if len(fields) != 2 || strings.ToLower(fields[0]) != vendorID {
+			continue
+		}
+		vendorName := firstWord(fields[1])
+
+		// Device entries are listed directly below their vendor, indented by
+		// a single tab; a double-tab line is a subvendor/subdevice entry, and
+		// a non-tab line starts the next vendor block.
+		for j := i + 1; j < len(lines) && strings.HasPrefix(lines[j], "\t"); j++ {
+			if strings.HasPrefix(lines[j], "\t\t") {
 				continue
 			}
-			if t > 0 {
-				stdoutLogger.Printf("SR-IOV capability is detected on the network interface: %s", netInterface.Name)
-				stdoutLogger.Printf("%d maximum supported number of virtual functions on network interface: %s", t, netInterface.Name)
-				features = append(features, "sriov")
-				numVfsPath := "/sys/class/net/" + netInterface.Name + "/device/sriov_numvfs"
-				numBytes, err := ioutil.ReadFile(numVfsPath)
-				if err != nil {
-					stderrLogger.Printf("SR-IOV not configured for network interface: %s: %s", netInterface.Name, err.Error())
-					continue
-				}
-				num := bytes.TrimSpace(numBytes)
-				n, err := strconv.Atoi(string(num))
-				if err != nil {
-					stderrLogger.Printf("Error in obtaining the configured number of virtual functions for network interface: %s: %s", netInterface.Name, err.Error())
-					continue
-				}
-				if n > 0 {
-					stderrLogger.Printf("%d virtual functions configured on network interface: %s", n, netInterface.Name)
-					features = append(features, "sriov-configured")
-					break
-				} else if n == 0 {
-					stderrLogger.Printf("SR-IOV not configured on network interface: %s", netInterface.Name)
-				}
+			devFields := strings.SplitN(strings.TrimPrefix(lines[j], "\t"), "  ", 2)
+			if len(devFields) != 2 || strings.ToLower(devFields[0]) != deviceID {
+				continue
 			}
+			return fmt.Sprintf("%s-%s", vendorName, firstWord(devFields[1])), true
 		}
+		return vendorName, true
 	}
-	return features, nil
+	return "", false
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/kubernetes-incubator/node-feature-discovery/pkg/source"
+)
+
+// writeFile creates parent directories as needed and writes contents to path.
+func writeFile(t *testing.T, p, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+		t.Fatalf("can't create %s: %s", path.Dir(p), err.Error())
+	}
+	if err := ioutil.WriteFile(p, []byte(contents), 0644); err != nil {
+		t.Fatalf("can't write %s: %s", p, err.Error())
+	}
+}
+
+// featureNames extracts the Name of every feature, for tests that only care
+// which presence-style labels were emitted.
+func featureNames(features []source.Feature) []string {
+	names := make([]string, 0, len(features))
+	for _, f := range features {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestDiscoverSwitchdev(t *testing.T) {
+	root, err := ioutil.TempDir("", "nfd-sysfs")
+	if err != nil {
+		t.Fatalf("can't create temp sysfs root: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	writeFile(t, path.Join(root, "class/net/eth0/compat/devlink/mode"), "switchdev\n")
+
+	s := networkSource{SysfsRoot: root}
+	got := s.discoverSwitchdev("eth0")
+	want := []string{"network-sriov.switchmode-switchdev-eth0"}
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("discoverSwitchdev() = %v, want %v", got, want)
+	}
+}
+
+func TestDiscoverVfRepresentors(t *testing.T) {
+	root, err := ioutil.TempDir("", "nfd-sysfs")
+	if err != nil {
+		t.Fatalf("can't create temp sysfs root: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	writeFile(t, path.Join(root, "class/net/eth0/phys_switch_id"), "deadbeef\n")
+	writeFile(t, path.Join(root, "class/net/eth0_0/phys_switch_id"), "deadbeef\n")
+	writeFile(t, path.Join(root, "class/net/eth0_0/phys_port_name"), "pf0vf0\n")
+
+	s := networkSource{SysfsRoot: root}
+	got := s.discoverVfRepresentors("eth0")
+	want := []string{"network-sriov.vf-representors-eth0"}
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("discoverVfRepresentors() = %v, want %v", got, want)
+	}
+}
+
+func TestDiscoverRdma(t *testing.T) {
+	root, err := ioutil.TempDir("", "nfd-sysfs")
+	if err != nil {
+		t.Fatalf("can't create temp sysfs root: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	writeFile(t, path.Join(root, "class/infiniband/mlx5_0/ports/1/link_layer"), "Ethernet\n")
+	writeFile(t, path.Join(root, "class/infiniband/mlx5_0/node_type"), "1: CA\n")
+	writeFile(t, path.Join(root, "class/infiniband/hfi1_0/ports/1/link_layer"), "InfiniBand\n")
+	writeFile(t, path.Join(root, "class/infiniband/irdma0/ports/1/link_layer"), "Ethernet\n")
+	writeFile(t, path.Join(root, "class/infiniband/irdma0/node_type"), "4: RNIC\n")
+
+	s := networkSource{SysfsRoot: root}
+	got := s.discoverRdma()
+	sort.Strings(got)
+	want := []string{"network-rdma.capable", "network-rdma.capable", "network-rdma.capable", "network-rdma.ib", "network-rdma.iwarp", "network-rdma.roce"}
+	sort.Strings(want)
+
+	if len(got) != len(want) {
+		t.Fatalf("discoverRdma() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("discoverRdma() = %v, want %v", got, want)
+		}
+	}
+}
+
+// TestDiscoverNetworkWithoutSriov verifies that switchdev, VF-representor and
+// vendor/device discovery still run for interfaces that don't expose
+// sriov_totalvfs at all, since those checks are independent of SR-IOV.
+func TestDiscoverNetworkWithoutSriov(t *testing.T) {
+	root, err := ioutil.TempDir("", "nfd-sysfs")
+	if err != nil {
+		t.Fatalf("can't create temp sysfs root: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	writeFile(t, path.Join(root, "class/net/eth0/compat/devlink/mode"), "switchdev\n")
+
+	s := networkSource{
+		SysfsRoot: root,
+		interfaces: func() ([]net.Interface, error) {
+			return []net.Interface{{Name: "eth0", Flags: net.FlagUp}}, nil
+		},
+	}
+	features, err := s.Discover()
+	if err != nil {
+		t.Fatalf("Discover() error: %s", err.Error())
+	}
+
+	got := featureNames(features)
+	want := "network-sriov.switchmode-switchdev-eth0"
+	found := false
+	for _, name := range got {
+		if name == want {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Errorf("Discover() = %v, want it to include %q even without sriov_totalvfs", got, want)
+	}
+}